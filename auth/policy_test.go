@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func testPolicies() *Policies {
+	return &Policies{
+		Roles: map[string]RolePolicy{
+			"viewer": {
+				Tables: map[string]TablePolicy{
+					"accounts": {
+						Operations: []string{"read"},
+						Columns:    []string{"id", "name"},
+						RowFilter:  "owner_id = ${claims.sub}",
+					},
+				},
+			},
+			"admin": {
+				Tables: map[string]TablePolicy{},
+			},
+		},
+	}
+}
+
+func TestAllowedDeniesUnrecognizedRole(t *testing.T) {
+	p := testPolicies()
+
+	if p.Allowed("", "accounts", "read") {
+		t.Fatal("expected an empty/unset role claim to be denied")
+	}
+	if p.Allowed("nobody", "accounts", "read") {
+		t.Fatal("expected a role absent from roles.yaml to be denied")
+	}
+}
+
+func TestAllowedEnforcesConfiguredOperations(t *testing.T) {
+	p := testPolicies()
+
+	if !p.Allowed("viewer", "accounts", "read") {
+		t.Fatal("expected viewer to be allowed to read accounts")
+	}
+	if p.Allowed("viewer", "accounts", "delete") {
+		t.Fatal("expected viewer to be denied deleting accounts")
+	}
+}
+
+func TestAllowedDefaultsToUnrestrictedForKnownRoleWithoutPolicy(t *testing.T) {
+	p := testPolicies()
+
+	if !p.Allowed("admin", "accounts", "delete") {
+		t.Fatal("expected a known role with no policy for a table to be unrestricted")
+	}
+}
+
+func TestColumnWhitelist(t *testing.T) {
+	p := testPolicies()
+
+	cols, ok := p.ColumnWhitelist("viewer", "accounts")
+	if !ok {
+		t.Fatal("expected a column whitelist to be configured for viewer/accounts")
+	}
+	if len(cols) != 2 || cols[0] != "id" || cols[1] != "name" {
+		t.Fatalf("unexpected whitelist: %v", cols)
+	}
+
+	if _, ok := p.ColumnWhitelist("admin", "accounts"); ok {
+		t.Fatal("expected no whitelist for a role/table without configured columns")
+	}
+}
+
+func TestRowFilterParameterizesClaimValue(t *testing.T) {
+	p := testPolicies()
+
+	clause, args, ok := p.RowFilter("viewer", "accounts", map[string]interface{}{"sub": "'; DROP TABLE accounts; --"})
+	if !ok {
+		t.Fatal("expected a row filter to be configured for viewer/accounts")
+	}
+
+	if strings.Contains(clause, "DROP TABLE") {
+		t.Fatalf("attacker-controlled claim value leaked into SQL text: %q", clause)
+	}
+	if clause != "owner_id = ?" {
+		t.Fatalf("unexpected clause: %q", clause)
+	}
+	if len(args) != 1 || args[0] != "'; DROP TABLE accounts; --" {
+		t.Fatalf("expected claim value to be bound as a parameter, got args %v", args)
+	}
+}
+
+func TestRowFilterAbsentWhenUnconfigured(t *testing.T) {
+	p := testPolicies()
+
+	if _, _, ok := p.RowFilter("admin", "accounts", nil); ok {
+		t.Fatal("expected no row filter for a role/table without one configured")
+	}
+}
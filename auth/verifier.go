@@ -0,0 +1,166 @@
+// Package auth provides pluggable bearer-token authentication (HS256 or
+// RS256 JWTs) and the role-based, per-table access policies layered on
+// top of it.
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config configures the JWT Verifier. Exactly one of Secret (for HS256)
+// or JWKSURL (for RS256) is expected to be set, matching the --jwt-secret
+// / --jwt-jwks-url flags.
+type Config struct {
+	Secret  string
+	JWKSURL string
+}
+
+// Verifier validates JWT bearer tokens and returns their claims.
+type Verifier struct {
+	config Config
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey // RS256 keys by `kid`, fetched from JWKSURL
+}
+
+// NewVerifier creates a Verifier from the given config.
+func NewVerifier(config Config) *Verifier {
+	return &Verifier{config: config, keys: make(map[string]*rsa.PublicKey)}
+}
+
+// Verify parses and validates tokenString, returning its claims.
+func (v *Verifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, v.keyFunc, jwt.WithValidMethods([]string{"HS256", "RS256"}))
+	if err != nil {
+		return nil, fmt.Errorf("auth: invalid token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("auth: invalid token")
+	}
+	return claims, nil
+}
+
+// keyFunc selects the verification key for a token based on its
+// signing algorithm, per jwt.Parse's KeyFunc contract.
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case "HS256":
+		if v.config.Secret == "" {
+			return nil, fmt.Errorf("auth: HS256 token presented but --jwt-secret is not configured")
+		}
+		return []byte(v.config.Secret), nil
+	case "RS256":
+		kid, _ := token.Header["kid"].(string)
+		return v.rsaKey(kid)
+	default:
+		return nil, fmt.Errorf("auth: unsupported signing method %q", token.Method.Alg())
+	}
+}
+
+// rsaKey returns the RS256 public key for kid, fetching (and caching)
+// the JWKS document on first use or on a cache miss.
+func (v *Verifier) rsaKey(kid string) (*rsa.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if err := v.refreshJWKS(); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// jwkSet and jwkKey model the minimal subset of RFC 7517 this package
+// needs: RSA signing keys.
+type jwkSet struct {
+	Keys []jwkKey `json:"keys"`
+}
+
+type jwkKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// refreshJWKS fetches and replaces the cached RS256 key set from
+// config.JWKSURL.
+func (v *Verifier) refreshJWKS() error {
+	if v.config.JWKSURL == "" {
+		return fmt.Errorf("auth: RS256 token presented but --jwt-jwks-url is not configured")
+	}
+
+	resp, err := http.Get(v.config.JWKSURL)
+	if err != nil {
+		return fmt.Errorf("auth: failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("auth: failed to read JWKS response: %w", err)
+	}
+
+	var set jwkSet
+	if err := json.Unmarshal(body, &set); err != nil {
+		return fmt.Errorf("auth: failed to parse JWKS response: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := decodeRSAPublicKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.mu.Unlock()
+	return nil
+}
+
+// decodeRSAPublicKey decodes the base64url-encoded modulus and exponent
+// of a JWK RSA key into an *rsa.PublicKey.
+func decodeRSAPublicKey(nStr, eStr string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(nStr)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(eStr)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := 0
+	for _, b := range eBytes {
+		exponent = exponent<<8 + int(b)
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: exponent}, nil
+}
@@ -0,0 +1,375 @@
+// Package cluster adds optional Raft-replicated clustering to sqlite-rest,
+// so that several instances can serve reads against consistent copies of
+// the same logical database while writes are funneled through a single
+// Raft leader. It is modeled on rqlite's architecture: every write is a
+// Raft log entry applied to each node's local SQLite file via the FSM
+// below, and reads are served locally with a caller-selectable
+// consistency level.
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+)
+
+// Config holds the flags needed to start a cluster Node.
+type Config struct {
+	NodeID    string
+	RaftAddr  string
+	RaftDir   string
+	Bootstrap bool // true for the first node that forms the cluster
+}
+
+// Database is the write interface the FSM uses to apply replicated
+// writes, returning the last insert ID and the number of rows affected.
+// It has no read method; this package performs no reads through it.
+// Keeping it as an interface (rather than depending on *sql.DB
+// directly) lets callers substitute a fake in tests.
+type Database interface {
+	Exec(query string, args ...interface{}) (int64, int64, error)
+}
+
+// command is a single replicated operation, logged verbatim to the Raft
+// log and re-applied by the FSM on every node. Type "exec" (the default,
+// for backward compatibility with commands logged before "register" was
+// introduced) replays Query/Args against the local database; "register"
+// records a node's externally reachable HTTP address so every node can
+// resolve where to forward writes when it isn't the leader.
+type command struct {
+	Type     string        `json:"type,omitempty"`
+	Query    string        `json:"query,omitempty"`
+	Args     []interface{} `json:"args,omitempty"`
+	RaftAddr string        `json:"raft_addr,omitempty"`
+	HTTPAddr string        `json:"http_addr,omitempty"`
+}
+
+// nodeRegistry maps each node's Raft address to its externally reachable
+// HTTP address. It is populated exclusively by replaying "register"
+// commands through the FSM, so every node ends up with the same mapping
+// regardless of which node a client talks to.
+type nodeRegistry struct {
+	mu    sync.RWMutex
+	addrs map[string]string
+}
+
+func newNodeRegistry() *nodeRegistry {
+	return &nodeRegistry{addrs: make(map[string]string)}
+}
+
+func (r *nodeRegistry) set(raftAddr, httpAddr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addrs[raftAddr] = httpAddr
+}
+
+func (r *nodeRegistry) get(raftAddr string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.addrs[raftAddr]
+}
+
+// fsm implements raft.FSM by replaying logged commands against the local
+// database connection.
+type fsm struct {
+	db       Database
+	dbPath   string
+	registry *nodeRegistry
+}
+
+func (f *fsm) Apply(log *raft.Log) interface{} {
+	var cmd command
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return fmt.Errorf("failed to unmarshal command: %w", err)
+	}
+
+	if cmd.Type == "register" {
+		f.registry.set(cmd.RaftAddr, cmd.HTTPAddr)
+		return nil
+	}
+
+	lastInsertID, rowsAffected, err := f.db.Exec(cmd.Query, cmd.Args...)
+	if err != nil {
+		return err
+	}
+	return applyResult{LastInsertID: lastInsertID, RowsAffected: rowsAffected}
+}
+
+// applyResult is returned from fsm.Apply through raft.ApplyFuture.Response().
+type applyResult struct {
+	LastInsertID int64
+	RowsAffected int64
+}
+
+// fsmSnapshot snapshots the SQLite file itself: the authoritative state is
+// that file, so Persist streams a full copy of it into the Raft snapshot,
+// and Restore installs that copy rather than re-playing every historical
+// write.
+type fsmSnapshot struct{ dbPath string }
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	f, err := os.Open(s.dbPath)
+	if err != nil {
+		sink.Cancel()
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(sink, f); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	return &fsmSnapshot{dbPath: f.dbPath}, nil
+}
+
+// Restore installs a snapshot produced by fsmSnapshot.Persist, replacing
+// the on-disk SQLite file with the one streamed from r. It is invoked by
+// raft whenever a lagging or newly-joined node catches up via a
+// compacted snapshot rather than by replaying the full log.
+func (f *fsm) Restore(r io.ReadCloser) error {
+	defer r.Close()
+
+	tmpPath := f.dbPath + ".restoring"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create restore file: %w", err)
+	}
+
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write restored snapshot: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to finalize restored snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, f.dbPath); err != nil {
+		return fmt.Errorf("failed to install restored snapshot: %w", err)
+	}
+	return nil
+}
+
+// Node wraps a *raft.Raft instance and the handful of cluster-management
+// operations exposed over HTTP (/status, /nodes, /join).
+type Node struct {
+	config   Config
+	raft     *raft.Raft
+	registry *nodeRegistry
+	httpAddr string
+}
+
+// New starts (or rejoins) a Raft node rooted at config.RaftDir, applying
+// replicated writes to db. httpAddr is this node's externally reachable
+// HTTP address, registered with the cluster so other nodes can forward
+// writes to it when it becomes leader.
+func New(config Config, dbPath string, db Database, httpAddr string) (*Node, error) {
+	if err := os.MkdirAll(config.RaftDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create raft dir: %w", err)
+	}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(config.NodeID)
+
+	addr, err := net.ResolveTCPAddr("tcp", config.RaftAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve raft address: %w", err)
+	}
+
+	transport, err := raft.NewTCPTransport(config.RaftAddr, addr, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raft transport: %w", err)
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(config.RaftDir, 2, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot store: %w", err)
+	}
+
+	// The log and stable stores share a single BoltDB file so that a
+	// node's Raft log, term/vote state, and cluster configuration all
+	// survive a process restart.
+	boltStore, err := raftboltdb.NewBoltStore(filepath.Join(config.RaftDir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create durable raft store: %w", err)
+	}
+	var logStore raft.LogStore = boltStore
+	var stableStore raft.StableStore = boltStore
+
+	registry := newNodeRegistry()
+	r, err := raft.NewRaft(raftConfig, &fsm{db: db, dbPath: dbPath, registry: registry}, logStore, stableStore, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raft node: %w", err)
+	}
+
+	if config.Bootstrap {
+		configuration := raft.Configuration{
+			Servers: []raft.Server{
+				{ID: raftConfig.LocalID, Address: transport.LocalAddr()},
+			},
+		}
+		r.BootstrapCluster(configuration)
+	}
+
+	node := &Node{config: config, raft: r, registry: registry, httpAddr: httpAddr}
+
+	if config.Bootstrap {
+		if node.waitForLeader(5 * time.Second) {
+			if err := node.registerSelf(); err != nil {
+				return nil, fmt.Errorf("failed to register node: %w", err)
+			}
+		}
+	}
+
+	return node, nil
+}
+
+// waitForLeader blocks until this node becomes Raft leader or timeout
+// elapses, returning whether it became leader in time. It is only used
+// right after BootstrapCluster, where this node is the sole voter and
+// leadership is expected to settle almost immediately.
+func (n *Node) waitForLeader(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if n.raft.State() == raft.Leader {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false
+}
+
+// registerSelf replicates this node's own Raft/HTTP address mapping,
+// called once after bootstrapping a brand-new cluster (a joining node is
+// instead registered by the leader inside Join).
+func (n *Node) registerSelf() error {
+	_, err := n.applyCommand(command{Type: "register", RaftAddr: n.config.RaftAddr, HTTPAddr: n.httpAddr})
+	return err
+}
+
+// Apply replicates a write through Raft. It returns an error if this node
+// is not the leader; callers are expected to forward the request to the
+// leader (see the APIServer's cluster-aware write path).
+func (n *Node) Apply(query string, args []interface{}) (lastInsertID, rowsAffected int64, err error) {
+	result, err := n.applyCommand(command{Type: "exec", Query: query, Args: args})
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.LastInsertID, result.RowsAffected, nil
+}
+
+// applyCommand replicates cmd through Raft and waits for it to be
+// committed. It returns an error if this node is not the leader.
+func (n *Node) applyCommand(cmd command) (applyResult, error) {
+	if n.raft.State() != raft.Leader {
+		return applyResult{}, fmt.Errorf("not the leader, current leader is %s", n.raft.Leader())
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return applyResult{}, err
+	}
+
+	future := n.raft.Apply(data, 10*time.Second)
+	if err := future.Error(); err != nil {
+		return applyResult{}, err
+	}
+
+	result, ok := future.Response().(applyResult)
+	if !ok {
+		if err, ok := future.Response().(error); ok {
+			return applyResult{}, err
+		}
+		return applyResult{}, nil
+	}
+	return result, nil
+}
+
+// Barrier blocks until all prior writes have been applied locally,
+// implementing the "strong" consistency level: the caller is guaranteed
+// to read state as of the most recent committed write.
+func (n *Node) Barrier(timeout time.Duration) error {
+	return n.raft.Barrier(timeout).Error()
+}
+
+// IsLeader reports whether this node is currently the Raft leader.
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+// LeaderAddr returns the Raft address of the current leader, if known.
+func (n *Node) LeaderAddr() string {
+	return string(n.raft.Leader())
+}
+
+// LeaderHTTPAddr returns the externally reachable HTTP address of the
+// current Raft leader, if known, resolved through the replicated node
+// registry. Callers use this to forward (redirect or proxy) writes made
+// against a non-leader node.
+func (n *Node) LeaderHTTPAddr() string {
+	return n.registry.get(n.LeaderAddr())
+}
+
+// Join adds a voting node to the cluster and replicates its HTTP address
+// so every node can resolve it later via LeaderHTTPAddr. It must be
+// called against the current leader; on any other node it returns an
+// error, which the /join HTTP handler reports together with the leader's
+// Raft address.
+func (n *Node) Join(nodeID, raftAddr, httpAddr string) error {
+	if !n.IsLeader() {
+		return fmt.Errorf("not the leader, current leader is %s", n.LeaderAddr())
+	}
+
+	future := n.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(raftAddr), 0, 0)
+	if err := future.Error(); err != nil {
+		return err
+	}
+
+	_, err := n.applyCommand(command{Type: "register", RaftAddr: raftAddr, HTTPAddr: httpAddr})
+	return err
+}
+
+// Status returns a snapshot of cluster membership and leadership,
+// suitable for serialization from the /status and /nodes endpoints.
+func (n *Node) Status() map[string]interface{} {
+	configFuture := n.raft.GetConfiguration()
+
+	var servers []map[string]string
+	if configFuture.Error() == nil {
+		for _, srv := range configFuture.Configuration().Servers {
+			servers = append(servers, map[string]string{
+				"id":      string(srv.ID),
+				"address": string(srv.Address),
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"node_id":   n.config.NodeID,
+		"state":     n.raft.State().String(),
+		"leader":    n.LeaderAddr(),
+		"is_leader": n.IsLeader(),
+		"servers":   servers,
+	}
+}
+
+// SnapshotPath returns where a compacted SQLite snapshot for this node
+// should live on disk, alongside the Raft log.
+func (n *Node) SnapshotPath() string {
+	return filepath.Join(n.config.RaftDir, "snapshot.db")
+}
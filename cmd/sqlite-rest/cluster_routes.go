@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// barrierTimeout bounds how long a `?level=strong` read waits for the
+// local Raft log to catch up to the last committed entry.
+const barrierTimeout = 5 * time.Second
+
+// applyConsistencyLevel honors the `?level=none|weak|strong` query
+// parameter against the cluster node, if clustering is enabled:
+//   - none (default): read the local state as-is.
+//   - weak: require this node to be the current leader.
+//   - strong: block until a Raft barrier confirms this node has applied
+//     every write committed so far.
+//
+// It returns false (after writing a response) if the request could not
+// be satisfied at the requested level.
+func (s *APIServer) applyConsistencyLevel(c *gin.Context) bool {
+	if s.cluster == nil {
+		return true
+	}
+
+	switch c.Query("level") {
+	case "weak":
+		if !s.cluster.IsLeader() {
+			c.JSON(http.StatusMisdirectedRequest, gin.H{
+				"error":  "this node is not the leader",
+				"leader": s.cluster.LeaderAddr(),
+			})
+			return false
+		}
+	case "strong":
+		if err := s.cluster.Barrier(barrierTimeout); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+
+	return true
+}
+
+// requireLeader reports whether this node can serve a write locally. When
+// clustering is disabled it always can; when this node isn't the current
+// Raft leader, the request is transparently proxied to the leader instead
+// so write-mode behaves the same regardless of which node a client talks
+// to, and false is returned so the caller's handler stops processing.
+func (s *APIServer) requireLeader(c *gin.Context) bool {
+	if s.cluster == nil || s.cluster.IsLeader() {
+		return true
+	}
+
+	leaderAddr := s.cluster.LeaderHTTPAddr()
+	if leaderAddr == "" {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "no known cluster leader"})
+		return false
+	}
+
+	httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: leaderAddr}).ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+	return false
+}
+
+// handleClusterStatus serves GET /status.
+func (s *APIServer) handleClusterStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, s.cluster.Status())
+}
+
+// handleClusterNodes serves GET /nodes.
+func (s *APIServer) handleClusterNodes(c *gin.Context) {
+	c.JSON(http.StatusOK, s.cluster.Status()["servers"])
+}
+
+// handleClusterJoin serves POST /join, adding the requesting node as a
+// Raft voter. Must be called against the leader.
+func (s *APIServer) handleClusterJoin(c *gin.Context) {
+	var req struct {
+		NodeID   string `json:"node_id"`
+		RaftAddr string `json:"raft_addr"`
+		HTTPAddr string `json:"http_addr"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.NodeID == "" || req.RaftAddr == "" || req.HTTPAddr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "node_id, raft_addr and http_addr are required"})
+		return
+	}
+
+	if err := s.cluster.Join(req.NodeID, req.RaftAddr, req.HTTPAddr); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":  fmt.Sprintf("failed to join cluster: %v", err),
+			"leader": s.cluster.LeaderAddr(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "joined"})
+}
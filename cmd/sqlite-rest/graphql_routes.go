@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+// handleGraphQL serves POST /graphql, executing the request against the
+// schema built from table introspection.
+func (s *APIServer) handleGraphQL(c *gin.Context) {
+	var req struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         s.gqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// graphiQLHTML renders a minimal GraphiQL playground pointed at /graphql,
+// loaded from the public unpkg CDN so no extra assets need to be vendored.
+const graphiQLHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <title>sqlite-rest GraphQL playground</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql/graphiql.min.css" />
+</head>
+<body style="margin:0">
+  <div id="graphiql" style="height:100vh"></div>
+  <script src="https://unpkg.com/react/umd/react.production.min.js"></script>
+  <script src="https://unpkg.com/react-dom/umd/react-dom.production.min.js"></script>
+  <script src="https://unpkg.com/graphiql/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: '/graphql' });
+    ReactDOM.render(
+      React.createElement(GraphiQL, { fetcher }),
+      document.getElementById('graphiql'),
+    );
+  </script>
+</body>
+</html>`
+
+// handleGraphQLUI serves the GraphiQL playground page for interactively
+// exploring the /graphql endpoint.
+func (s *APIServer) handleGraphQLUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(graphiQLHTML))
+}
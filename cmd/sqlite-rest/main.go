@@ -3,18 +3,26 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/d0mnk/sqlite-rest/auth"
+	"github.com/d0mnk/sqlite-rest/cluster"
+	gqlschema "github.com/d0mnk/sqlite-rest/graphql"
+	"github.com/d0mnk/sqlite-rest/sqlbuilder"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/graphql-go/graphql"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -25,6 +33,17 @@ type Config struct {
 	Mode     string
 	Username string
 	Password string
+	Write    bool
+
+	RaftAddr      string
+	RaftDir       string
+	NodeID        string
+	Join          string
+	AdvertiseAddr string
+
+	JWTSecret  string
+	JWTJWKSURL string
+	RolesPath  string
 }
 
 type TableInfo struct {
@@ -40,11 +59,50 @@ type ColumnInfo struct {
 }
 
 type APIServer struct {
-	db     *sql.DB
-	router *gin.Engine
-	config *Config
-	tables []TableInfo
-	server *http.Server
+	db                *sql.DB
+	router            *gin.Engine
+	config            *Config
+	tables            []TableInfo
+	server            *http.Server
+	cluster           *cluster.Node
+	gqlSchema         graphql.Schema
+	writeDB           *sql.DB
+	streamBroadcaster *changeBroadcaster
+	allowlist         *sqlbuilder.Allowlist
+	stmtCache         *sqlbuilder.StmtCache
+	jwtVerifier       *auth.Verifier
+	policies          *auth.Policies
+}
+
+// stmtCacheCapacity bounds how many prepared statements are kept warm
+// across distinct query shapes.
+const stmtCacheCapacity = 256
+
+// dbExecutor adapts *sql.DB to the cluster.Database interface the Raft FSM
+// uses to replay replicated writes.
+type dbExecutor struct {
+	db *sql.DB
+}
+
+func (e dbExecutor) Exec(query string, args ...interface{}) (int64, int64, error) {
+	result, err := e.db.Exec(query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lastInsertID, _ := result.LastInsertId()
+	rowsAffected, _ := result.RowsAffected()
+	return lastInsertID, rowsAffected, nil
+}
+
+// exec applies a write either through the Raft cluster (when clustering
+// is enabled, replicating it to every node) or directly against the
+// local database.
+func (s *APIServer) exec(query string, args ...interface{}) (lastInsertID, rowsAffected int64, err error) {
+	if s.cluster != nil {
+		return s.cluster.Apply(query, args)
+	}
+	return dbExecutor{db: s.writeDB}.Exec(query, args...)
 }
 
 func parseConfig() (*Config, error) {
@@ -56,6 +114,15 @@ func parseConfig() (*Config, error) {
 	flag.StringVar(&config.Mode, "mode", "release", "Server mode (debug/release)")
 	flag.StringVar(&config.Username, "username", "", "Basic auth username")
 	flag.StringVar(&config.Password, "password", "", "Basic auth password")
+	flag.BoolVar(&config.Write, "write", false, "Enable POST/PATCH/DELETE write routes")
+	flag.StringVar(&config.RaftAddr, "raft-addr", "", "Raft bind address (enables clustering)")
+	flag.StringVar(&config.RaftDir, "raft-dir", "raft", "Directory for Raft log/snapshot data")
+	flag.StringVar(&config.NodeID, "node-id", "", "Unique node ID (required when --raft-addr is set)")
+	flag.StringVar(&config.Join, "join", "", "Address of an existing cluster node; when set, this node does not bootstrap a new cluster. The address is not contacted: the node joins once a POST /join naming it reaches the leader")
+	flag.StringVar(&config.AdvertiseAddr, "advertise-addr", "", "HTTP address other nodes should use to reach this node (defaults to host:port)")
+	flag.StringVar(&config.JWTSecret, "jwt-secret", "", "Shared secret for verifying HS256 JWT bearer tokens")
+	flag.StringVar(&config.JWTJWKSURL, "jwt-jwks-url", "", "JWKS URL for verifying RS256 JWT bearer tokens")
+	flag.StringVar(&config.RolesPath, "roles", "", "Path to a roles.yaml file describing per-role table access policies")
 
 	flag.Parse()
 
@@ -82,10 +149,14 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("database file does not exist: %s", config.DBPath)
 	}
 
+	if config.RaftAddr != "" && config.NodeID == "" {
+		return fmt.Errorf("--node-id is required when --raft-addr is set")
+	}
+
 	return nil
 }
 
-func configureDatabase(db *sql.DB) error {
+func configureDatabase(db *sql.DB, write bool) error {
 	pragmas := []string{
 		"PRAGMA cache_size = -2097152",    // 2GB cache
 		"PRAGMA page_size = 32768",        // 32KB pages
@@ -93,7 +164,10 @@ func configureDatabase(db *sql.DB) error {
 		"PRAGMA synchronous = OFF",        // Disable sync
 		"PRAGMA temp_store = MEMORY",      // Memory temp tables
 		"PRAGMA mmap_size = 137438953472", // 128GB mmap
-		"PRAGMA query_only = 1",           // Force read-only
+	}
+
+	if !write {
+		pragmas = append(pragmas, "PRAGMA query_only = 1") // Force read-only
 	}
 
 	for _, pragma := range pragmas {
@@ -104,8 +178,33 @@ func configureDatabase(db *sql.DB) error {
 	return nil
 }
 
+// authMiddleware prefers a JWT bearer token, falling back to the existing
+// Basic Auth check when no Verifier is configured at all. When a Verifier
+// is configured, every request must present a valid Bearer token — it is
+// never valid to silently fall through to "no auth configured".
 func (s *APIServer) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if s.jwtVerifier != nil {
+			header := c.GetHeader("Authorization")
+			if !strings.HasPrefix(header, "Bearer ") {
+				c.Header("WWW-Authenticate", "Bearer")
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+				return
+			}
+
+			claims, err := s.jwtVerifier.Verify(strings.TrimPrefix(header, "Bearer "))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+
+			role, _ := claims["role"].(string)
+			c.Set("claims", claims)
+			c.Set("role", role)
+			c.Next()
+			return
+		}
+
 		// Skip auth if no credentials are configured
 		if s.config.Username == "" && s.config.Password == "" {
 			c.Next()
@@ -124,6 +223,62 @@ func (s *APIServer) authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// requestRole returns the role claim attached by authMiddleware, or ""
+// when the request was not authenticated via a JWT (e.g. Basic Auth or
+// auth disabled).
+func requestRole(c *gin.Context) string {
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
+	return roleStr
+}
+
+// requestClaims returns the JWT claims attached by authMiddleware, or nil
+// when the request was not authenticated via a JWT.
+func requestClaims(c *gin.Context) map[string]interface{} {
+	claims, ok := c.Get("claims")
+	if !ok {
+		return nil
+	}
+	mapClaims, _ := claims.(jwt.MapClaims)
+	return mapClaims
+}
+
+// authorizeOperation checks the role's policy for operation on tableName,
+// aborting the request with 403 if it is disallowed. It returns false
+// when the request has already been aborted.
+func (s *APIServer) authorizeOperation(c *gin.Context, tableName, operation string) bool {
+	if s.policies == nil {
+		return true
+	}
+	if !s.policies.Allowed(requestRole(c), tableName, operation) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return false
+	}
+	return true
+}
+
+// rowFilterClause returns the role's row_filter for tableName AND-ed into
+// a WHERE clause, if one is configured.
+func (s *APIServer) rowFilterClause(c *gin.Context, tableName string) (string, []interface{}) {
+	if s.policies == nil {
+		return "", nil
+	}
+	clause, args, ok := s.policies.RowFilter(requestRole(c), tableName, requestClaims(c))
+	if !ok {
+		return "", nil
+	}
+	return clause, args
+}
+
+func (s *APIServer) handleWhoami(c *gin.Context) {
+	claims := requestClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusOK, gin.H{"authenticated": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authenticated": true, "role": requestRole(c), "claims": claims})
+}
+
 func NewAPIServer(config *Config) (*APIServer, error) {
 	gin.SetMode(config.Mode)
 
@@ -136,7 +291,7 @@ func NewAPIServer(config *Config) (*APIServer, error) {
 		return nil, fmt.Errorf("database ping failed: %v", err)
 	}
 
-	if err := configureDatabase(db); err != nil {
+	if err := configureDatabase(db, config.Write); err != nil {
 		return nil, err
 	}
 
@@ -150,10 +305,103 @@ func NewAPIServer(config *Config) (*APIServer, error) {
 		return nil, fmt.Errorf("failed to load table info: %v", err)
 	}
 
+	server.allowlist = sqlbuilder.NewAllowlist(toSQLBuilderTables(server.tables))
+	server.stmtCache = sqlbuilder.NewStmtCache(stmtCacheCapacity)
+
+	server.streamBroadcaster = newChangeBroadcaster(changeRingCapacity)
+	registerHookedDriver(server.streamBroadcaster, server.fetchRowByRowID)
+
+	// The writable connection (and its relaxed query_only pragma) is only
+	// opened when this node can actually produce writes: either directly
+	// via --write, or indirectly by applying writes replicated to it
+	// through Raft. Otherwise db's own query_only=1 pragma is the only
+	// connection to the file, preserving the read-only guarantee --write
+	// is supposed to provide.
+	var writeDB *sql.DB
+	if config.Write || config.RaftAddr != "" {
+		writeDB, err = sql.Open(hookedDriverName, config.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open writer connection: %v", err)
+		}
+		writeDB.SetMaxOpenConns(1) // the update hook only fires on this single connection
+		if err := configureDatabase(writeDB, true); err != nil {
+			return nil, fmt.Errorf("failed to configure writer connection: %v", err)
+		}
+		server.writeDB = writeDB
+	}
+
+	if config.RaftAddr != "" {
+		advertiseAddr := config.AdvertiseAddr
+		if advertiseAddr == "" {
+			advertiseAddr = fmt.Sprintf("%s:%d", config.Host, config.Port)
+		}
+
+		node, err := cluster.New(cluster.Config{
+			NodeID:    config.NodeID,
+			RaftAddr:  config.RaftAddr,
+			RaftDir:   config.RaftDir,
+			Bootstrap: config.Join == "",
+		}, config.DBPath, dbExecutor{db: writeDB}, advertiseAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start cluster node: %v", err)
+		}
+		server.cluster = node
+	}
+
+	schema, err := gqlschema.Build(toGraphQLTables(server.tables), db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build graphql schema: %v", err)
+	}
+	server.gqlSchema = schema
+
+	if config.JWTSecret != "" || config.JWTJWKSURL != "" {
+		server.jwtVerifier = auth.NewVerifier(auth.Config{Secret: config.JWTSecret, JWKSURL: config.JWTJWKSURL})
+	}
+	if config.RolesPath != "" {
+		policies, err := auth.LoadPolicies(config.RolesPath)
+		if err != nil {
+			return nil, err
+		}
+		server.policies = policies
+	}
+
 	server.setupRoutes()
 
 	return server, nil
 }
+
+// toSQLBuilderTables converts the introspected table metadata into the
+// decoupled shape the sqlbuilder package expects.
+func toSQLBuilderTables(tables []TableInfo) []sqlbuilder.TableInfo {
+	converted := make([]sqlbuilder.TableInfo, len(tables))
+	for i, table := range tables {
+		columns := make([]sqlbuilder.ColumnInfo, len(table.Columns))
+		for j, col := range table.Columns {
+			columns[j] = sqlbuilder.ColumnInfo{Name: col.Name}
+		}
+		converted[i] = sqlbuilder.TableInfo{Name: table.Name, Columns: columns}
+	}
+	return converted
+}
+
+// toGraphQLTables converts the introspected table metadata into the
+// decoupled shape the graphql package expects.
+func toGraphQLTables(tables []TableInfo) []gqlschema.TableInfo {
+	converted := make([]gqlschema.TableInfo, len(tables))
+	for i, table := range tables {
+		columns := make([]gqlschema.ColumnInfo, len(table.Columns))
+		for j, col := range table.Columns {
+			columns[j] = gqlschema.ColumnInfo{
+				Name:       col.Name,
+				Type:       col.Type,
+				NotNull:    col.NotNull,
+				PrimaryKey: col.PrimaryKey,
+			}
+		}
+		converted[i] = gqlschema.TableInfo{Name: table.Name, Columns: columns}
+	}
+	return converted
+}
 func (s *APIServer) loadTableInfo() error {
 	rows, err := s.db.Query(`
 		SELECT name FROM sqlite_master
@@ -207,27 +455,66 @@ func (s *APIServer) setupRoutes() {
 	s.router.Use(s.authMiddleware())
 
 	s.router.GET("/", s.handleAPIInfo)
+	s.router.GET("/openapi.json", s.handleOpenAPISpec)
+	s.router.GET("/docs", s.handleSwaggerUI)
+	s.router.GET("/auth/whoami", s.handleWhoami)
+
+	if s.cluster != nil {
+		s.router.GET("/status", s.handleClusterStatus)
+		s.router.GET("/nodes", s.handleClusterNodes)
+		s.router.POST("/join", s.handleClusterJoin)
+	}
+
+	s.router.POST("/graphql", s.handleGraphQL)
+	s.router.GET("/graphql/ui", s.handleGraphQLUI)
 
 	for _, table := range s.tables {
 		group := s.router.Group("/" + table.Name)
 		{
 			group.GET("", s.handleTableQuery(table.Name))
 			group.GET("/:id", s.handleRecordQuery(table.Name))
+			group.GET("/stream", s.handleTableStream(table.Name))
+
+			if s.config.Write {
+				group.POST("", s.handleTableInsert(table.Name))
+				group.PATCH("/:id", s.handleRecordUpdate(table.Name))
+				group.DELETE("/:id", s.handleRecordDelete(table.Name))
+			}
 		}
 	}
 }
 
 func (s *APIServer) handleAPIInfo(c *gin.Context) {
+	endpoints := []string{
+		"GET /openapi.json",
+		"GET /docs",
+		"GET /auth/whoami",
+		"POST /graphql",
+		"GET /graphql/ui",
+	}
+	if s.cluster != nil {
+		endpoints = append(endpoints, "GET /status", "GET /nodes", "POST /join")
+	}
+
 	info := gin.H{
 		"tables":    s.tables,
-		"endpoints": make([]string, 0),
+		"endpoints": endpoints,
 	}
 
 	for _, table := range s.tables {
 		info["endpoints"] = append(info["endpoints"].([]string),
 			fmt.Sprintf("GET /%s", table.Name),
 			fmt.Sprintf("GET /%s/:id", table.Name),
+			fmt.Sprintf("GET /%s/stream", table.Name),
 		)
+
+		if s.config.Write {
+			info["endpoints"] = append(info["endpoints"].([]string),
+				fmt.Sprintf("POST /%s", table.Name),
+				fmt.Sprintf("PATCH /%s/:id", table.Name),
+				fmt.Sprintf("DELETE /%s/:id", table.Name),
+			)
+		}
 	}
 
 	c.JSON(http.StatusOK, info)
@@ -235,7 +522,15 @@ func (s *APIServer) handleAPIInfo(c *gin.Context) {
 
 func (s *APIServer) handleTableQuery(tableName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get pagination parameters with defaults
+		if !s.applyConsistencyLevel(c) {
+			return
+		}
+		if !s.authorizeOperation(c, tableName, "read") {
+			return
+		}
+
+		// Get pagination parameters with defaults, then let a Range header
+		// (PostgREST-style) override them.
 		limit := 100
 		offset := 0
 		if limitStr := c.Query("limit"); limitStr != "" {
@@ -248,78 +543,104 @@ func (s *APIServer) handleTableQuery(tableName string) gin.HandlerFunc {
 				offset = o
 			}
 		}
+		if rng, ok := parseRangeHeader(c.GetHeader("Range")); ok {
+			offset = rng.offset
+			limit = rng.limit
+		}
 
-		// Get total count
-		var total int
-		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
-		if err := s.db.QueryRow(countQuery).Scan(&total); err != nil {
+		quotedTable, err := s.allowlist.TableRef(tableName)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Build query
-		query := fmt.Sprintf("SELECT * FROM %s", tableName)
-		var params []interface{}
+		whereClause, params, err := s.buildWhereClause(tableName, c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		// Handle filters
-		whereConditions := []string{}
-		for key, values := range c.Request.URL.Query() {
-			if key != "limit" && key != "offset" && key != "order" {
-				whereConditions = append(whereConditions, fmt.Sprintf("%s = ?", key))
-				params = append(params, values[0])
+		if filterClause, filterArgs := s.rowFilterClause(c, tableName); filterClause != "" {
+			if whereClause != "" {
+				whereClause += " AND " + filterClause
+			} else {
+				whereClause = filterClause
 			}
+			params = append(params, filterArgs...)
 		}
 
-		if len(whereConditions) > 0 {
-			query += " WHERE " + strings.Join(whereConditions, " AND ")
+		selectColumns, err := s.applyColumnWhitelist(c, tableName, parseSelect(c.Query("select")))
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
 		}
 
-		// Handle ordering
-		if order := c.Query("order"); order != "" {
-			query += fmt.Sprintf(" ORDER BY %s", order)
+		selectClause, err := s.allowlist.BuildSelectClause(tableName, selectColumns)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var orderClause string
+		if rawOrder := c.Query("order"); rawOrder != "" {
+			orderClause, err = s.allowlist.BuildOrderClause(tableName, toBuilderOrderTerms(parseOrder(rawOrder)))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 		}
 
-		// Add pagination
-		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+		// Get total count, honoring the same filters as the page query.
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", quotedTable)
+		if whereClause != "" {
+			countQuery += " WHERE " + whereClause
+		}
 
-		// Execute query
-		rows, err := s.db.Query(query, params...)
+		countStmt, err := s.stmtCache.Prepare(s.db, countQuery, countQuery)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		defer rows.Close()
 
-		// Process results
-		columns, err := rows.Columns()
+		var total int
+		if err := countStmt.QueryRow(params...).Scan(&total); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Build the page query, sharing a single prepared statement across
+		// requests that differ only in bound values.
+		query := fmt.Sprintf("SELECT %s FROM %s", selectClause, quotedTable)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+		}
+		if orderClause != "" {
+			query += " ORDER BY " + orderClause
+		}
+		query += " LIMIT ? OFFSET ?"
+
+		pageStmt, err := s.stmtCache.Prepare(s.db, query, query)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		var results []map[string]interface{}
-		for rows.Next() {
-			values := make([]interface{}, len(columns))
-			valuePtrs := make([]interface{}, len(columns))
-			for i := range values {
-				valuePtrs[i] = &values[i]
-			}
+		rows, err := pageStmt.Query(append(append([]interface{}{}, params...), limit, offset)...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
 
-			if err := rows.Scan(valuePtrs...); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
+		results, err := scanRows(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-			row := make(map[string]interface{})
-			for i, col := range columns {
-				val := values[i]
-				if b, ok := val.([]byte); ok {
-					row[col] = string(b)
-				} else {
-					row[col] = val
-				}
-			}
-			results = append(results, row)
+		c.Header("Content-Range", contentRange(offset, len(results), total))
+		if c.GetHeader("Prefer") == "count=exact" {
+			c.Header("Prefer", "count=exact")
 		}
 
 		// Return paginated response
@@ -332,52 +653,449 @@ func (s *APIServer) handleTableQuery(tableName string) gin.HandlerFunc {
 	}
 }
 
+// buildWhereClause validates and parameterizes every non-reserved query
+// parameter as a filter, sorting by column name first so that requests
+// differing only in parameter order still share a cached prepared
+// statement.
+func (s *APIServer) buildWhereClause(tableName string, params map[string][]string) (string, []interface{}, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !reservedParams[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var clauses []string
+	var args []interface{}
+	for _, key := range keys {
+		for _, raw := range params[key] {
+			filter, err := s.allowlist.BuildFilter(tableName, key, raw)
+			if err != nil {
+				return "", nil, err
+			}
+			clauses = append(clauses, filter.SQL)
+			args = append(args, filter.Args...)
+		}
+	}
+
+	return strings.Join(clauses, " AND "), args, nil
+}
+
+// applyColumnWhitelist narrows requested to the role's configured column
+// whitelist for tableName, defaulting to the whitelist itself when
+// requested is empty. It errors if requested names a column the role's
+// policy does not permit.
+func (s *APIServer) applyColumnWhitelist(c *gin.Context, tableName string, requested []string) ([]string, error) {
+	if s.policies == nil {
+		return requested, nil
+	}
+
+	whitelist, ok := s.policies.ColumnWhitelist(requestRole(c), tableName)
+	if !ok {
+		return requested, nil
+	}
+	if len(requested) == 0 {
+		return whitelist, nil
+	}
+
+	allowed := make(map[string]bool, len(whitelist))
+	for _, col := range whitelist {
+		allowed[col] = true
+	}
+	for _, col := range requested {
+		if !allowed[col] {
+			return nil, fmt.Errorf("column %q is not permitted for this role", col)
+		}
+	}
+	return requested, nil
+}
+
+// authorizeColumnWrite reports whether the role may write col on
+// tableName, aborting the request with 403 if the role's configured
+// column whitelist excludes it. A role/table with no configured
+// whitelist may write any column, the same "unconfigured = unrestricted"
+// default applyColumnWhitelist uses for reads.
+func (s *APIServer) authorizeColumnWrite(c *gin.Context, tableName, col string) bool {
+	if s.policies == nil {
+		return true
+	}
+	whitelist, ok := s.policies.ColumnWhitelist(requestRole(c), tableName)
+	if !ok {
+		return true
+	}
+	for _, allowed := range whitelist {
+		if allowed == col {
+			return true
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("column %q is not permitted for this role", col)})
+	return false
+}
+
+// toBuilderOrderTerms converts parsed order terms into the shape the
+// sqlbuilder package validates and quotes.
+func toBuilderOrderTerms(terms []orderTerm) []sqlbuilder.OrderTerm {
+	converted := make([]sqlbuilder.OrderTerm, len(terms))
+	for i, term := range terms {
+		converted[i] = sqlbuilder.OrderTerm{
+			Column:    term.column,
+			Direction: term.direction,
+			Nulls:     term.nulls,
+		}
+	}
+	return converted
+}
+
+// scanRows drains a *sql.Rows into a slice of column-name-keyed maps,
+// converting driver []byte values to strings the way the rest of this
+// package expects for JSON encoding.
+func scanRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{})
+		for i, col := range columns {
+			val := values[i]
+			if b, ok := val.([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = val
+			}
+		}
+		results = append(results, row)
+	}
+
+	return results, nil
+}
+
 func (s *APIServer) handleRecordQuery(tableName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !s.applyConsistencyLevel(c) {
+			return
+		}
+		if !s.authorizeOperation(c, tableName, "read") {
+			return
+		}
+
 		id := c.Param("id")
 
-		query := fmt.Sprintf("SELECT * FROM %s WHERE id = ?", tableName)
-		rows, err := s.db.Query(query, id)
+		quotedTable, err := s.allowlist.TableRef(tableName)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		defer rows.Close()
 
-		columns, err := rows.Columns()
+		selectColumns, err := s.applyColumnWhitelist(c, tableName, parseSelect(c.Query("select")))
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		selectClause, err := s.allowlist.BuildSelectClause(tableName, selectColumns)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", selectClause, quotedTable)
+		args := []interface{}{id}
+		if filterClause, filterArgs := s.rowFilterClause(c, tableName); filterClause != "" {
+			query += " AND " + filterClause
+			args = append(args, filterArgs...)
+		}
+
+		stmt, err := s.stmtCache.Prepare(s.db, query, query)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		if !rows.Next() {
+		rows, err := stmt.Query(args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
+
+		results, err := scanRows(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(results) == 0 {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
 			return
 		}
 
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+		c.JSON(http.StatusOK, results[0])
+	}
+}
+
+// handleTableInsert handles POST /:table, accepting either a single JSON
+// object or a JSON array of objects for bulk insert. Each object's keys
+// must name existing columns on the table.
+func (s *APIServer) handleTableInsert(tableName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.requireLeader(c) {
+			return
+		}
+		if !s.authorizeOperation(c, tableName, "create") {
+			return
 		}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
+		records, err := decodeRecords(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(records) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "request body must contain at least one record"})
+			return
+		}
+
+		quotedTable, err := s.allowlist.TableRef(tableName)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		result := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				result[col] = string(b)
-			} else {
-				result[col] = val
+		var ids []int64
+		for _, record := range records {
+			columns := make([]string, 0, len(record))
+			placeholders := make([]string, 0, len(record))
+			values := make([]interface{}, 0, len(record))
+			for col, val := range record {
+				if !s.authorizeColumnWrite(c, tableName, col) {
+					return
+				}
+				quotedColumn, err := s.allowlist.ColumnRef(tableName, col)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				columns = append(columns, quotedColumn)
+				placeholders = append(placeholders, "?")
+				values = append(values, val)
+			}
+
+			query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+				quotedTable, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+			id, _, err := s.exec(query, values...)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		if c.GetHeader("Prefer") == "return=minimal" {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		inserted, err := s.fetchByIDs(tableName, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(inserted) == 1 {
+			c.JSON(http.StatusCreated, inserted[0])
+			return
+		}
+		c.JSON(http.StatusCreated, inserted)
+	}
+}
+
+// handleRecordUpdate handles PATCH /:table/:id, applying the JSON body's
+// fields to the matching row.
+func (s *APIServer) handleRecordUpdate(tableName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.requireLeader(c) {
+			return
+		}
+		if !s.authorizeOperation(c, tableName, "update") {
+			return
+		}
+
+		id := c.Param("id")
+
+		var fields map[string]interface{}
+		if err := c.ShouldBindJSON(&fields); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(fields) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "request body must contain at least one field"})
+			return
+		}
+
+		quotedTable, err := s.allowlist.TableRef(tableName)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		assignments := make([]string, 0, len(fields))
+		values := make([]interface{}, 0, len(fields)+1)
+		for col, val := range fields {
+			if !s.authorizeColumnWrite(c, tableName, col) {
+				return
 			}
+			quotedColumn, err := s.allowlist.ColumnRef(tableName, col)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			assignments = append(assignments, fmt.Sprintf("%s = ?", quotedColumn))
+			values = append(values, val)
+		}
+		values = append(values, id)
+
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", quotedTable, strings.Join(assignments, ", "))
+		if filterClause, filterArgs := s.rowFilterClause(c, tableName); filterClause != "" {
+			query += " AND " + filterClause
+			values = append(values, filterArgs...)
+		}
+		_, affected, err := s.exec(query, values...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if affected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			return
+		}
+
+		if c.GetHeader("Prefer") == "return=minimal" {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		rows, err := s.db.Query(fmt.Sprintf("SELECT * FROM %s WHERE id = ?", quotedTable), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer rows.Close()
+
+		results, err := scanRows(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, results[0])
+	}
+}
+
+// handleRecordDelete handles DELETE /:table/:id.
+func (s *APIServer) handleRecordDelete(tableName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.requireLeader(c) {
+			return
+		}
+		if !s.authorizeOperation(c, tableName, "delete") {
+			return
+		}
+
+		id := c.Param("id")
+
+		quotedTable, err := s.allowlist.TableRef(tableName)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", quotedTable)
+		args := []interface{}{id}
+		if filterClause, filterArgs := s.rowFilterClause(c, tableName); filterClause != "" {
+			query += " AND " + filterClause
+			args = append(args, filterArgs...)
+		}
+		_, affected, err := s.exec(query, args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if affected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// fetchByIDs re-reads rows by primary key, used to build return=representation
+// responses after an insert.
+func (s *APIServer) fetchByIDs(tableName string, ids []int64) ([]map[string]interface{}, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	quotedTable, err := s.allowlist.TableRef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE id IN (%s)", quotedTable, strings.Join(placeholders, ","))
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRows(rows)
+}
+
+// decodeRecords accepts either a single JSON object or a JSON array of
+// objects as the request body.
+func decodeRecords(c *gin.Context) ([]map[string]interface{}, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var records []map[string]interface{}
+		if err := json.Unmarshal(body, &records); err != nil {
+			return nil, err
 		}
+		return records, nil
+	}
 
-		c.JSON(http.StatusOK, result)
+	var record map[string]interface{}
+	if err := json.Unmarshal(body, &record); err != nil {
+		return nil, err
 	}
+	return []map[string]interface{}{record}, nil
 }
 
 func (s *APIServer) logStartup() {
@@ -385,7 +1103,8 @@ func (s *APIServer) logStartup() {
 	log.Printf("Mode: %s", s.config.Mode)
 	log.Printf("Address: %s:%d", s.config.Host, s.config.Port)
 	log.Printf("Database: %s", s.config.DBPath)
-	log.Printf("Auth Enabled: %v", s.config.Username != "" || s.config.Password != "")
+	log.Printf("Auth Enabled: %v", s.config.Username != "" || s.config.Password != "" || s.jwtVerifier != nil)
+	log.Printf("Role Policies: %v", s.policies != nil)
 
 	pragmas := []string{
 		"cache_size", "page_size", "journal_mode", "synchronous",
@@ -418,6 +1137,16 @@ func (s *APIServer) Shutdown(ctx context.Context) error {
 		return err
 	}
 
+	if err := s.stmtCache.Close(); err != nil {
+		return err
+	}
+
+	if s.writeDB != nil {
+		if err := s.writeDB.Close(); err != nil {
+			return err
+		}
+	}
+
 	return s.db.Close()
 }
 
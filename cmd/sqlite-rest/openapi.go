@@ -0,0 +1,285 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sqliteAffinityToJSONSchema maps a SQLite column type (by the keywords of
+// the affinity rules) to the JSON Schema type used in the generated
+// OpenAPI document. A type matching none of the keywords checked below,
+// which SQLite itself gives NUMERIC affinity, is described as a string.
+// See https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func sqliteAffinityToJSONSchema(sqliteType string) (schemaType string, format string) {
+	t := strings.ToUpper(sqliteType)
+
+	switch {
+	case strings.Contains(t, "INT"):
+		return "integer", ""
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "number", ""
+	case strings.Contains(t, "BLOB"), t == "":
+		return "string", "binary"
+	case strings.Contains(t, "NUMERIC"), strings.Contains(t, "DECIMAL"), strings.Contains(t, "BOOLEAN"), strings.Contains(t, "DATE"):
+		return "number", ""
+	default:
+		return "string", ""
+	}
+}
+
+// columnSchema builds the JSON Schema object for a single table column.
+func columnSchema(col ColumnInfo) gin.H {
+	schemaType, format := sqliteAffinityToJSONSchema(col.Type)
+
+	schema := gin.H{"type": schemaType}
+	if format != "" {
+		schema["format"] = format
+	}
+	if col.NotNull {
+		schema["nullable"] = false
+	} else {
+		schema["nullable"] = true
+	}
+
+	return schema
+}
+
+// tableSchema builds the OpenAPI schema object describing a full table row.
+func tableSchema(table TableInfo) gin.H {
+	properties := gin.H{}
+	var required []string
+
+	for _, col := range table.Columns {
+		properties[col.Name] = columnSchema(col)
+		if col.NotNull {
+			required = append(required, col.Name)
+		}
+	}
+
+	schema := gin.H{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// filterParameters builds the per-column `?column=operator.value` query
+// parameters advertised for a table's list endpoint.
+func filterParameters(table TableInfo) []gin.H {
+	params := make([]gin.H, 0, len(table.Columns)+2)
+
+	for _, col := range table.Columns {
+		params = append(params, gin.H{
+			"name":        col.Name,
+			"in":          "query",
+			"required":    false,
+			"description": "Filter on " + col.Name + ", e.g. `eq.value`, `gte.value`, `like.*value*`, `in.(a,b)`, or `not.eq.value`",
+			"schema":      gin.H{"type": "string"},
+		})
+	}
+
+	params = append(params,
+		gin.H{"name": "select", "in": "query", "description": "Comma-separated list of columns to return", "schema": gin.H{"type": "string"}},
+		gin.H{"name": "order", "in": "query", "description": "Comma-separated `column.asc|desc.nullsfirst|nullslast` terms", "schema": gin.H{"type": "string"}},
+		gin.H{"name": "limit", "in": "query", "schema": gin.H{"type": "integer"}},
+		gin.H{"name": "offset", "in": "query", "schema": gin.H{"type": "integer"}},
+	)
+
+	return params
+}
+
+// errorResponse is the shared OpenAPI response object for the error
+// shapes this API returns (the gin.H{"error": ...} payloads of the
+// handlers in main.go).
+var errorResponse = gin.H{
+	"description": "Error",
+	"content": gin.H{
+		"application/json": gin.H{
+			"schema": gin.H{
+				"type":       "object",
+				"properties": gin.H{"error": gin.H{"type": "string"}},
+			},
+		},
+	},
+}
+
+// pagedResponse builds the OpenAPI response object for a table's list
+// endpoint, wrapping the table schema in this API's pagination envelope.
+func pagedResponse(table TableInfo) gin.H {
+	return gin.H{
+		"description": "Paginated " + table.Name + " rows",
+		"content": gin.H{
+			"application/json": gin.H{
+				"schema": gin.H{
+					"type": "object",
+					"properties": gin.H{
+						"total":  gin.H{"type": "integer"},
+						"offset": gin.H{"type": "integer"},
+						"limit":  gin.H{"type": "integer"},
+						"data":   gin.H{"type": "array", "items": gin.H{"$ref": "#/components/schemas/" + table.Name}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// recordResponse builds the OpenAPI response object for a single-record
+// endpoint.
+func recordResponse(table TableInfo, description string) gin.H {
+	return gin.H{
+		"description": description,
+		"content": gin.H{
+			"application/json": gin.H{
+				"schema": gin.H{"$ref": "#/components/schemas/" + table.Name},
+			},
+		},
+	}
+}
+
+// buildOpenAPISpec assembles the full OpenAPI 3.0 document describing
+// every auto-generated table endpoint.
+func (s *APIServer) buildOpenAPISpec() gin.H {
+	schemas := gin.H{}
+	paths := gin.H{}
+
+	for _, table := range s.tables {
+		schemas[table.Name] = tableSchema(table)
+
+		collectionPath := gin.H{
+			"get": gin.H{
+				"summary":    "List " + table.Name,
+				"parameters": filterParameters(table),
+				"responses": gin.H{
+					"200": pagedResponse(table),
+					"500": errorResponse,
+				},
+			},
+		}
+
+		recordPath := gin.H{
+			"get": gin.H{
+				"summary": "Get a single " + table.Name + " record by id",
+				"parameters": []gin.H{
+					{"name": "id", "in": "path", "required": true, "schema": gin.H{"type": "string"}},
+				},
+				"responses": gin.H{
+					"200": recordResponse(table, "A "+table.Name+" record"),
+					"404": errorResponse,
+					"500": errorResponse,
+				},
+			},
+		}
+
+		if s.config.Write {
+			collectionPath["post"] = gin.H{
+				"summary": "Create one or more " + table.Name + " records",
+				"requestBody": gin.H{
+					"content": gin.H{
+						"application/json": gin.H{
+							"schema": gin.H{
+								"oneOf": []gin.H{
+									{"$ref": "#/components/schemas/" + table.Name},
+									{"type": "array", "items": gin.H{"$ref": "#/components/schemas/" + table.Name}},
+								},
+							},
+						},
+					},
+				},
+				"responses": gin.H{
+					"201": gin.H{
+						"description": "The created record, or an array of records for a bulk insert",
+						"content": gin.H{
+							"application/json": gin.H{
+								"schema": gin.H{
+									"oneOf": []gin.H{
+										{"$ref": "#/components/schemas/" + table.Name},
+										{"type": "array", "items": gin.H{"$ref": "#/components/schemas/" + table.Name}},
+									},
+								},
+							},
+						},
+					},
+					"400": errorResponse,
+					"500": errorResponse,
+				},
+			}
+
+			recordPath["patch"] = gin.H{
+				"summary": "Update a " + table.Name + " record by id",
+				"parameters": []gin.H{
+					{"name": "id", "in": "path", "required": true, "schema": gin.H{"type": "string"}},
+				},
+				"responses": gin.H{
+					"200": recordResponse(table, "The updated record"),
+					"404": errorResponse,
+					"500": errorResponse,
+				},
+			}
+
+			recordPath["delete"] = gin.H{
+				"summary": "Delete a " + table.Name + " record by id",
+				"parameters": []gin.H{
+					{"name": "id", "in": "path", "required": true, "schema": gin.H{"type": "string"}},
+				},
+				"responses": gin.H{
+					"204": gin.H{"description": "Deleted"},
+					"404": errorResponse,
+					"500": errorResponse,
+				},
+			}
+		}
+
+		paths["/"+table.Name] = collectionPath
+		paths["/"+table.Name+"/{id}"] = recordPath
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "sqlite-rest",
+			"description": "Auto-generated REST API over a SQLite database",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas,
+		},
+	}
+}
+
+// handleOpenAPISpec serves the generated OpenAPI 3.0 document.
+func (s *APIServer) handleOpenAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, s.buildOpenAPISpec())
+}
+
+// swaggerUIHTML renders a minimal Swagger UI page pointed at /openapi.json,
+// loaded from the public unpkg CDN so no extra assets need to be vendored.
+const swaggerUIHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <title>sqlite-rest API docs</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: '/openapi.json', dom_id: '#swagger-ui' });
+    };
+  </script>
+</body>
+</html>`
+
+// handleSwaggerUI serves the Swagger UI page for interactively browsing
+// /openapi.json.
+func (s *APIServer) handleSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIHTML))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// reservedParams are query-string keys that control the request itself
+// rather than naming a column filter.
+var reservedParams = map[string]bool{
+	"limit":  true,
+	"offset": true,
+	"order":  true,
+	"select": true,
+	"level":  true,
+}
+
+// orderTerm is a single parsed `column.direction.nulls` clause from the
+// `order` query parameter.
+type orderTerm struct {
+	column    string
+	direction string
+	nulls     string
+}
+
+// parseOrder parses the `order` query parameter, e.g.
+// `order=age.desc.nullsfirst,name.asc`. The resulting column names are
+// not yet validated; callers must run them through sqlbuilder before use.
+func parseOrder(raw string) []orderTerm {
+	if raw == "" {
+		return nil
+	}
+
+	var terms []orderTerm
+	for _, part := range strings.Split(raw, ",") {
+		fields := strings.Split(part, ".")
+		term := orderTerm{column: fields[0], direction: "ASC"}
+
+		for _, f := range fields[1:] {
+			switch strings.ToLower(f) {
+			case "asc":
+				term.direction = "ASC"
+			case "desc":
+				term.direction = "DESC"
+			case "nullsfirst":
+				term.nulls = "NULLS FIRST"
+			case "nullslast":
+				term.nulls = "NULLS LAST"
+			}
+		}
+
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// parseSelect parses the `select` query parameter into a column list,
+// returning nil (meaning "all columns") when absent.
+func parseSelect(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	cols := strings.Split(raw, ",")
+	for i, c := range cols {
+		cols[i] = strings.TrimSpace(c)
+	}
+	return cols
+}
+
+// rangeSpec is a parsed `Range: <first>-<last>` request header.
+type rangeSpec struct {
+	offset int
+	limit  int
+}
+
+// parseRangeHeader parses a PostgREST-style `Range` header of the form
+// "0-24" into an offset/limit pair. ok is false if the header is absent
+// or malformed.
+func parseRangeHeader(header string) (rangeSpec, bool) {
+	if header == "" {
+		return rangeSpec{}, false
+	}
+
+	parts := strings.SplitN(header, "-", 2)
+	if len(parts) != 2 {
+		return rangeSpec{}, false
+	}
+
+	first, err1 := strconv.Atoi(parts[0])
+	last, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || last < first {
+		return rangeSpec{}, false
+	}
+
+	return rangeSpec{offset: first, limit: last - first + 1}, true
+}
+
+// contentRange renders the `Content-Range` response header value for a
+// page of rows out of a known total.
+func contentRange(offset, returned, total int) string {
+	if returned == 0 {
+		return fmt.Sprintf("*/%d", total)
+	}
+	return fmt.Sprintf("%d-%d/%d", offset, offset+returned-1, total)
+}
@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestParseOrderParsesDirectionAndNulls(t *testing.T) {
+	terms := parseOrder("age.desc.nullsfirst,name.asc")
+
+	if len(terms) != 2 {
+		t.Fatalf("expected 2 terms, got %d", len(terms))
+	}
+	if terms[0] != (orderTerm{column: "age", direction: "DESC", nulls: "NULLS FIRST"}) {
+		t.Fatalf("unexpected first term: %+v", terms[0])
+	}
+	if terms[1] != (orderTerm{column: "name", direction: "ASC"}) {
+		t.Fatalf("unexpected second term: %+v", terms[1])
+	}
+}
+
+func TestParseOrderDefaultsToAscending(t *testing.T) {
+	terms := parseOrder("name")
+
+	if len(terms) != 1 || terms[0].direction != "ASC" {
+		t.Fatalf("unexpected terms: %+v", terms)
+	}
+}
+
+func TestParseOrderEmpty(t *testing.T) {
+	if terms := parseOrder(""); terms != nil {
+		t.Fatalf("expected nil for empty order, got %+v", terms)
+	}
+}
+
+func TestParseSelectSplitsAndTrims(t *testing.T) {
+	cols := parseSelect("id, name ,age")
+
+	want := []string{"id", "name", "age"}
+	if len(cols) != len(want) {
+		t.Fatalf("unexpected columns: %v", cols)
+	}
+	for i, c := range want {
+		if cols[i] != c {
+			t.Fatalf("column %d: got %q, want %q", i, cols[i], c)
+		}
+	}
+}
+
+func TestParseSelectEmpty(t *testing.T) {
+	if cols := parseSelect(""); cols != nil {
+		t.Fatalf("expected nil for empty select, got %v", cols)
+	}
+}
+
+func TestParseRangeHeader(t *testing.T) {
+	spec, ok := parseRangeHeader("0-24")
+	if !ok {
+		t.Fatal("expected a valid range header to parse")
+	}
+	if spec.offset != 0 || spec.limit != 25 {
+		t.Fatalf("unexpected range: %+v", spec)
+	}
+}
+
+func TestParseRangeHeaderRejectsMalformed(t *testing.T) {
+	cases := []string{"", "24", "24-10", "a-b"}
+	for _, header := range cases {
+		if _, ok := parseRangeHeader(header); ok {
+			t.Fatalf("expected %q to be rejected", header)
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	if got := contentRange(0, 25, 100); got != "0-24/100" {
+		t.Fatalf("contentRange(0, 25, 100) = %q", got)
+	}
+	if got := contentRange(10, 0, 100); got != "*/100" {
+		t.Fatalf("contentRange(10, 0, 100) = %q", got)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// changeEvent is a single row-level change emitted on a table's SSE
+// stream.
+type changeEvent struct {
+	ID    int64                  `json:"id"`
+	Table string                 `json:"table"`
+	Op    string                 `json:"op"` // insert, update, delete
+	RowID int64                  `json:"rowid"`
+	Row   map[string]interface{} `json:"row,omitempty"`
+}
+
+// changeRing is a bounded, in-memory ring buffer of recent changeEvents
+// for one table, plus the set of live SSE subscribers. An event is dropped
+// for a subscriber whose buffer is full rather than allowed to block
+// publishers; the subscriber itself stays registered.
+type changeRing struct {
+	mu          sync.Mutex
+	capacity    int
+	events      []changeEvent
+	nextID      int64
+	subscribers map[chan changeEvent]struct{}
+}
+
+func newChangeRing(capacity int) *changeRing {
+	return &changeRing{
+		capacity:    capacity,
+		subscribers: make(map[chan changeEvent]struct{}),
+	}
+}
+
+// publish assigns the next event ID, appends it to the ring (evicting the
+// oldest entry once full), and fans it out to every live subscriber.
+func (r *changeRing) publish(ev changeEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	ev.ID = r.nextID
+
+	r.events = append(r.events, ev)
+	if len(r.events) > r.capacity {
+		r.events = r.events[len(r.events)-r.capacity:]
+	}
+
+	for ch := range r.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Slow consumer: drop the event rather than block the writer.
+		}
+	}
+}
+
+// subscribe registers a new subscriber channel and returns it along with
+// an unsubscribe function the caller must invoke when done.
+func (r *changeRing) subscribe() (chan changeEvent, func()) {
+	ch := make(chan changeEvent, 64)
+
+	r.mu.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.mu.Unlock()
+
+	return ch, func() {
+		r.mu.Lock()
+		delete(r.subscribers, ch)
+		r.mu.Unlock()
+		close(ch)
+	}
+}
+
+// since returns every buffered event with an ID greater than lastEventID,
+// for `Last-Event-ID` reconnection replay.
+func (r *changeRing) since(lastEventID int64) []changeEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var missed []changeEvent
+	for _, ev := range r.events {
+		if ev.ID > lastEventID {
+			missed = append(missed, ev)
+		}
+	}
+	return missed
+}
+
+// changeBroadcaster owns one changeRing per table, created lazily.
+type changeBroadcaster struct {
+	mu       sync.Mutex
+	rings    map[string]*changeRing
+	capacity int
+}
+
+func newChangeBroadcaster(ringCapacity int) *changeBroadcaster {
+	return &changeBroadcaster{
+		rings:    make(map[string]*changeRing),
+		capacity: ringCapacity,
+	}
+}
+
+func (b *changeBroadcaster) ringFor(table string) *changeRing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ring, ok := b.rings[table]
+	if !ok {
+		ring = newChangeRing(b.capacity)
+		b.rings[table] = ring
+	}
+	return ring
+}
+
+// changeRingCapacity bounds how many recent events per table are kept in
+// memory for Last-Event-ID replay.
+const changeRingCapacity = 256
+
+// hookedDriverName is the database/sql driver name registered for the
+// dedicated writer connection that SQLite update hooks fire on.
+const hookedDriverName = "sqlite3_with_update_hook"
+
+var registerHookedDriverOnce sync.Once
+
+// registerHookedDriver registers the hookedDriverName driver exactly
+// once per process. Every new connection opened from it gets a
+// sqlite3_update_hook that publishes to broadcaster, resolving the
+// changed row's current column values via resolveRow (except on delete,
+// where the row no longer exists).
+func registerHookedDriver(broadcaster *changeBroadcaster, resolveRow func(table string, rowid int64) (map[string]interface{}, error)) {
+	registerHookedDriverOnce.Do(func() {
+		sql.Register(hookedDriverName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				conn.RegisterUpdateHook(func(op int, _ string, table string, rowid int64) {
+					opName, ok := updateHookOpNames[op]
+					if !ok {
+						return
+					}
+
+					ev := changeEvent{Table: table, Op: opName, RowID: rowid}
+					if opName != "delete" {
+						if row, err := resolveRow(table, rowid); err == nil {
+							ev.Row = row
+						}
+					}
+					broadcaster.ringFor(table).publish(ev)
+				})
+				return nil
+			},
+		})
+	})
+}
+
+var updateHookOpNames = map[int]string{
+	sqlite3.SQLITE_INSERT: "insert",
+	sqlite3.SQLITE_UPDATE: "update",
+	sqlite3.SQLITE_DELETE: "delete",
+}
+
+// fetchRowByRowID re-reads a table's row by SQLite's implicit rowid, used
+// to attach current column values to insert/update change events.
+func (s *APIServer) fetchRowByRowID(table string, rowid int64) (map[string]interface{}, error) {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT * FROM %s WHERE rowid = ?", table), rowid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results, err := scanRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("rowid %d no longer exists in %s", rowid, table)
+	}
+	return results[0], nil
+}
+
+// parseLastEventID parses the `Last-Event-ID` header (or its `?lastEventId`
+// query-string fallback for clients that can't set custom headers on an
+// EventSource request), returning 0 if absent or malformed.
+func parseLastEventID(header string) int64 {
+	id, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// matchesStreamFilters reports whether an event's row satisfies every
+// `?column=value` filter given on the /stream request. Filters are
+// evaluated in Go against the row already attached to the event, since
+// the row was read off a different connection than the one SQL filtering
+// would run against.
+func matchesStreamFilters(ev changeEvent, filters map[string][]string) bool {
+	if ev.Row == nil {
+		return len(filters) == 0
+	}
+
+	for column, values := range filters {
+		val, ok := ev.Row[column]
+		if !ok {
+			return false
+		}
+		if fmt.Sprintf("%v", val) != values[0] {
+			return false
+		}
+	}
+	return true
+}
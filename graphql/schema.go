@@ -0,0 +1,369 @@
+// Package graphql builds a GraphQL schema from table introspection data,
+// mirroring the auto-discovery approach the REST side uses in
+// loadTableInfo: one query field per table returning a paginated
+// connection, plus a single-record lookup by primary key.
+package graphql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// ColumnInfo mirrors the subset of table column metadata this package
+// needs. It is a separate type (rather than importing the REST package's
+// ColumnInfo) to keep this package free of a dependency on cmd/sqlite-rest.
+type ColumnInfo struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey bool
+}
+
+// TableInfo mirrors the subset of table metadata this package needs.
+type TableInfo struct {
+	Name    string
+	Columns []ColumnInfo
+}
+
+// Querier is the read-only subset of *sql.DB the generated resolvers use.
+// Schema generation never issues writes, matching the read-only mode
+// configureDatabase puts the connection in.
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scalarType maps a SQLite column affinity to the closest GraphQL scalar.
+func scalarType(sqliteType string) *graphql.Scalar {
+	t := strings.ToUpper(sqliteType)
+	switch {
+	case strings.Contains(t, "INT"):
+		return graphql.Int
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"), strings.Contains(t, "NUMERIC"):
+		return graphql.Float
+	default:
+		return graphql.String
+	}
+}
+
+// Build constructs a GraphQL schema with one query field per table.
+func Build(tables []TableInfo, db Querier) (graphql.Schema, error) {
+	queryFields := graphql.Fields{}
+
+	for _, table := range tables {
+		table := table // capture for closures
+
+		objectType := graphql.NewObject(graphql.ObjectConfig{
+			Name:   strings.Title(table.Name),
+			Fields: columnFields(table),
+		})
+
+		edgeType := graphql.NewObject(graphql.ObjectConfig{
+			Name: strings.Title(table.Name) + "Edge",
+			Fields: graphql.Fields{
+				"node": &graphql.Field{Type: objectType},
+			},
+		})
+
+		pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+			Name: strings.Title(table.Name) + "PageInfo",
+			Fields: graphql.Fields{
+				"hasNextPage": &graphql.Field{Type: graphql.Boolean},
+			},
+		})
+
+		connectionType := graphql.NewObject(graphql.ObjectConfig{
+			Name: strings.Title(table.Name) + "Connection",
+			Fields: graphql.Fields{
+				"edges":      &graphql.Field{Type: graphql.NewList(edgeType)},
+				"pageInfo":   &graphql.Field{Type: pageInfoType},
+				"totalCount": &graphql.Field{Type: graphql.Int},
+			},
+		})
+
+		queryFields[table.Name] = &graphql.Field{
+			Type:    connectionType,
+			Args:    connectionArgs(table),
+			Resolve: listResolver(table, db),
+		}
+
+		primaryKey := primaryKeyColumn(table)
+		if primaryKey != "" {
+			queryFields[table.Name+"ById"] = &graphql.Field{
+				Type: objectType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: recordResolver(table, primaryKey, db),
+			}
+		}
+	}
+
+	schemaConfig := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queryFields}),
+	}
+
+	return graphql.NewSchema(schemaConfig)
+}
+
+// columnFields builds one GraphQL field per column, resolving straight
+// out of the row map produced by the SQL scan.
+func columnFields(table TableInfo) graphql.Fields {
+	fields := graphql.Fields{}
+	for _, col := range table.Columns {
+		col := col
+		fields[col.Name] = &graphql.Field{
+			Type: scalarType(col.Type),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				row, _ := p.Source.(map[string]interface{})
+				return row[col.Name], nil
+			},
+		}
+	}
+	return fields
+}
+
+// filterOperators are the per-scalar-column comparison arguments each
+// table's list field accepts, e.g. `age_gt: 18`.
+var filterOperators = map[string]string{
+	"eq": "=", "neq": "!=", "gt": ">", "lt": "<", "in": "IN",
+}
+
+// connectionArgs builds the filter/orderBy/limit/offset arguments for a
+// table's list field.
+func connectionArgs(table TableInfo) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{
+		"limit":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 100},
+		"offset":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		"orderBy": &graphql.ArgumentConfig{Type: graphql.String},
+	}
+
+	for _, col := range table.Columns {
+		colType := scalarType(col.Type)
+		for op := range filterOperators {
+			argType := graphql.Input(colType)
+			if op == "in" {
+				argType = graphql.NewList(colType)
+			}
+			args[fmt.Sprintf("%s_%s", col.Name, op)] = &graphql.ArgumentConfig{Type: argType}
+		}
+	}
+
+	return args
+}
+
+// primaryKeyColumn returns the first primary key column's name, or "id"
+// as a fallback when the table has an id column, matching the convention
+// the REST handlers assume. It returns "" when the table has neither.
+func primaryKeyColumn(table TableInfo) string {
+	for _, col := range table.Columns {
+		if col.PrimaryKey {
+			return col.Name
+		}
+	}
+	for _, col := range table.Columns {
+		if col.Name == "id" {
+			return "id"
+		}
+	}
+	return ""
+}
+
+// listResolver runs the paginated, filtered table query and shapes the
+// result into the edges/pageInfo/totalCount connection.
+func listResolver(table TableInfo, db Querier) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		columns := requestedColumns(p, table)
+
+		var whereClauses []string
+		var args []interface{}
+		for argName, value := range p.Args {
+			for _, col := range table.Columns {
+				for op, sqlOp := range filterOperators {
+					if argName != fmt.Sprintf("%s_%s", col.Name, op) {
+						continue
+					}
+					if op == "in" {
+						values, _ := value.([]interface{})
+						placeholders := make([]string, len(values))
+						for i, v := range values {
+							placeholders[i] = "?"
+							args = append(args, v)
+						}
+						whereClauses = append(whereClauses, fmt.Sprintf("%s IN (%s)", col.Name, strings.Join(placeholders, ",")))
+					} else {
+						whereClauses = append(whereClauses, fmt.Sprintf("%s %s ?", col.Name, sqlOp))
+						args = append(args, value)
+					}
+				}
+			}
+		}
+
+		limit, _ := p.Args["limit"].(int)
+		offset, _ := p.Args["offset"].(int)
+
+		query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table.Name)
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", table.Name)
+		if len(whereClauses) > 0 {
+			query += " WHERE " + strings.Join(whereClauses, " AND ")
+			countQuery += " WHERE " + strings.Join(whereClauses, " AND ")
+		}
+		if orderBy, ok := p.Args["orderBy"].(string); ok && orderBy != "" {
+			clause, err := buildOrderClause(table, orderBy)
+			if err != nil {
+				return nil, err
+			}
+			query += " ORDER BY " + clause
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+
+		var total int
+		if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		records, err := scanRows(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		edges := make([]map[string]interface{}, len(records))
+		for i, record := range records {
+			edges[i] = map[string]interface{}{"node": record}
+		}
+
+		return map[string]interface{}{
+			"edges":      edges,
+			"totalCount": total,
+			"pageInfo":   map[string]interface{}{"hasNextPage": offset+len(records) < total},
+		}, nil
+	}
+}
+
+// buildOrderClause validates an `orderBy` argument of the form
+// `<column>_<asc|desc>` against table's known columns before it is
+// concatenated into SQL, the same allowlisting discipline the REST side
+// applies via sqlbuilder.
+func buildOrderClause(table TableInfo, orderBy string) (string, error) {
+	column, direction := orderBy, "ASC"
+	if idx := strings.LastIndex(orderBy, "_"); idx != -1 {
+		switch strings.ToUpper(orderBy[idx+1:]) {
+		case "ASC":
+			column, direction = orderBy[:idx], "ASC"
+		case "DESC":
+			column, direction = orderBy[:idx], "DESC"
+		}
+	}
+
+	for _, col := range table.Columns {
+		if col.Name == column {
+			return fmt.Sprintf("%s %s", column, direction), nil
+		}
+	}
+	return "", fmt.Errorf("graphql: unknown orderBy column %q", column)
+}
+
+// recordResolver looks up a single row by primary key.
+func recordResolver(table TableInfo, primaryKey string, db Querier) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		columns := requestedColumns(p, table)
+
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(columns, ", "), table.Name, primaryKey)
+		rows, err := db.Query(query, p.Args["id"])
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		records, err := scanRows(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		return records[0], nil
+	}
+}
+
+// requestedColumns inspects the GraphQL selection set to find which
+// scalar columns were actually asked for (walking every nested selection
+// set, which covers the `edges.node.*` shape of a connection), so the generated SQL only
+// selects what the client will read. It falls back to every column if
+// the selection set can't be resolved.
+func requestedColumns(p graphql.ResolveParams, table TableInfo) []string {
+	valid := map[string]bool{}
+	for _, col := range table.Columns {
+		valid[col.Name] = true
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	var walk func(selections []ast.Selection)
+	walk = func(selections []ast.Selection) {
+		for _, sel := range selections {
+			field, ok := sel.(*ast.Field)
+			if !ok || field.Name == nil {
+				continue
+			}
+			name := field.Name.Value
+			if valid[name] && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			if field.SelectionSet != nil {
+				walk(field.SelectionSet.Selections)
+			}
+		}
+	}
+
+	for _, fieldAST := range p.Info.FieldASTs {
+		if fieldAST.SelectionSet != nil {
+			walk(fieldAST.SelectionSet.Selections)
+		}
+	}
+
+	if len(names) == 0 {
+		for _, col := range table.Columns {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+// scanRows drains rows into a slice of column-keyed maps, converting
+// driver []byte values to strings for JSON-friendly output.
+func scanRows(rows *sql.Rows, columns []string) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{})
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		results = append(results, row)
+	}
+	return results, nil
+}
@@ -0,0 +1,103 @@
+package sqlbuilder
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+)
+
+// StmtCache is an LRU cache of prepared statements keyed by a caller-
+// supplied canonical shape (e.g. table + sorted filter columns + order +
+// whether limit/offset are present), so that structurally identical
+// queries differing only in bound values reuse one prepared statement
+// instead of being re-parsed by SQLite on every request.
+type StmtCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // front = most recently used
+}
+
+type stmtCacheEntry struct {
+	key  string
+	stmt *sql.Stmt
+}
+
+// NewStmtCache creates a cache holding at most capacity prepared
+// statements.
+func NewStmtCache(capacity int) *StmtCache {
+	return &StmtCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Prepare returns the cached *sql.Stmt for key if present, moving it to
+// the front of the LRU order. Otherwise it prepares query against db,
+// caches the result, and evicts the least recently used entry if the
+// cache is now over capacity.
+func (c *StmtCache) Prepare(db *sql.DB, key, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		stmt := elem.Value.(*stmtCacheEntry).stmt
+		c.mu.Unlock()
+		return stmt, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another goroutine may have raced us to prepare the same key; keep
+	// whichever entry is already cached and close the redundant one.
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		stmt.Close()
+		return elem.Value.(*stmtCacheEntry).stmt, nil
+	}
+
+	elem := c.order.PushFront(&stmtCacheEntry{key: key, stmt: stmt})
+	c.items[key] = elem
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			entry := oldest.Value.(*stmtCacheEntry)
+			delete(c.items, entry.key)
+			entry.stmt.Close()
+		}
+	}
+
+	return stmt, nil
+}
+
+// Len reports how many statements are currently cached.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Close closes every cached statement and empties the cache.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		if err := elem.Value.(*stmtCacheEntry).stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	return firstErr
+}